@@ -0,0 +1,47 @@
+// Copyright 2020 huiyi<yi.webmaster@hotmail.com>. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+// $Id: compressedpacketheader_test.go
+
+package mysql
+
+import "testing"
+
+// TestCompressedPacketHeaderRoundTrip covers the compressed-frame header
+// encode/decode that packetDecompressor.nextFrame relies on to drive the
+// chunk0-4 streaming ring buffer. nextFrame itself takes a *mysqlConn,
+// which isn't defined anywhere in this tree snapshot, so the full
+// readNext/fill/nextFrame pipeline can't be exercised here; this test
+// covers the deterministic, mysqlConn-free part of that logic.
+func TestCompressedPacketHeaderRoundTrip(t *testing.T) {
+	var h compressedPacketHeader
+	h.setPayload(0xABCDEF)
+	h.setSequence(0x42)
+	h.setUncompressedLength(0x123456)
+
+	if got := h.payload(); got != 0xABCDEF {
+		t.Errorf("payload() = %#x, want %#x", got, 0xABCDEF)
+	}
+	if got := h.sequence(); got != 0x42 {
+		t.Errorf("sequence() = %#x, want %#x", got, 0x42)
+	}
+	if got := h.uncompressedLength(); got != 0x123456 {
+		t.Errorf("uncompressedLength() = %#x, want %#x", got, 0x123456)
+	}
+
+	var h2 compressedPacketHeader
+	h2.reset(h[:])
+	if h2 != h {
+		t.Errorf("reset() did not reproduce the original header: got %v, want %v", h2, h)
+	}
+
+	// length 0 is the sentinel for "this frame's payload wasn't
+	// compressed" in nextFrame.
+	var h3 compressedPacketHeader
+	h3.setUncompressedLength(0)
+	if got := h3.uncompressedLength(); got != 0 {
+		t.Errorf("uncompressedLength() = %#x, want 0", got)
+	}
+}
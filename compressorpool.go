@@ -0,0 +1,85 @@
+// Copyright 2020 huiyi<yi.webmaster@hotmail.com>. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+// $Id: compressorpool.go
+
+package mysql
+
+import "sync"
+
+// CompressorFactory builds the compressor/decompressor pair for one named
+// compression algorithm (e.g. "zlib" or "zstd"). Register an alternate
+// implementation with RegisterCompressor to use it instead of the package
+// default, for example an adapter around github.com/klauspost/compress/zlib
+// or a parallel implementation of the same algorithm. The zlibCompressor and
+// zlibDecompressor types it returns, and their methods, are unexported, so a
+// CompressorFactory can only be implemented inside this package.
+type CompressorFactory interface {
+	NewCompressor(level int) zlibCompressor
+	NewDecompressor() zlibDecompressor
+}
+
+var compressorFactories = make(map[string]CompressorFactory)
+
+// RegisterCompressor makes a CompressorFactory available under name for
+// connections that select that compression algorithm. It mirrors
+// database/sql.Register and the package's dialer registration, and is
+// typically called from an init function in this package.
+func RegisterCompressor(name string, f CompressorFactory) {
+	if f == nil {
+		panic("mysql: RegisterCompressor compressor factory is nil")
+	}
+	if _, dup := compressorFactories[name]; dup {
+		panic("mysql: RegisterCompressor called twice for compressor " + name)
+	}
+	compressorFactories[name] = f
+}
+
+// compressorPoolKey identifies a pool of interchangeable compressor
+// instances: same algorithm, same level.
+type compressorPoolKey struct {
+	algorithm string
+	level     int
+}
+
+// compressorPools and decompressorPools cache idle compressor/decompressor
+// instances across connections, so a connection that calls release() (see
+// packetTransceiver.release in packettransceiver.go) hands its
+// already-initialized zlib.Writer (or equivalent) to the next Get instead
+// of it being garbage. As of packetTransceiver.readPacket/writePacket,
+// release() only runs on a connection's error paths; realizing the full
+// bursty-short-lived-connection win on a graceful close additionally
+// requires a release() call from mysqlConn.Close, which lives outside this
+// file.
+var compressorPools sync.Map   // compressorPoolKey -> *sync.Pool of zlibCompressor
+var decompressorPools sync.Map // algorithm name -> *sync.Pool of zlibDecompressor
+
+func getPooledCompressor(codec packetCodec, level int) zlibCompressor {
+	var key = compressorPoolKey{algorithm: codec.name(), level: level}
+	var pool, _ = compressorPools.LoadOrStore(key, &sync.Pool{
+		New: func() interface{} { return codec.newCompressor(level) },
+	})
+	return pool.(*sync.Pool).Get().(zlibCompressor)
+}
+
+func putPooledCompressor(codec packetCodec, level int, c zlibCompressor) {
+	var key = compressorPoolKey{algorithm: codec.name(), level: level}
+	if pool, ok := compressorPools.Load(key); ok {
+		pool.(*sync.Pool).Put(c)
+	}
+}
+
+func getPooledDecompressor(codec packetCodec) zlibDecompressor {
+	var pool, _ = decompressorPools.LoadOrStore(codec.name(), &sync.Pool{
+		New: func() interface{} { return codec.newDecompressor() },
+	})
+	return pool.(*sync.Pool).Get().(zlibDecompressor)
+}
+
+func putPooledDecompressor(codec packetCodec, d zlibDecompressor) {
+	if pool, ok := decompressorPools.Load(codec.name()); ok {
+		pool.(*sync.Pool).Put(d)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 huiyi<yi.webmaster@hotmail.com>. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+// $Id: compressorpool_test.go
+
+package mysql
+
+import "testing"
+
+func TestGetPutPooledCompressor(t *testing.T) {
+	codec := zlibCodec{}
+	level := testPoolCompressLevel()
+
+	c1 := getPooledCompressor(codec, level)
+	if c1 == nil {
+		t.Fatal("getPooledCompressor returned nil")
+	}
+	putPooledCompressor(codec, level, c1)
+
+	c2 := getPooledCompressor(codec, level)
+	if c2 != c1 {
+		t.Fatal("getPooledCompressor did not return the instance just released")
+	}
+}
+
+func TestGetPutPooledDecompressor(t *testing.T) {
+	codec := zlibCodec{}
+
+	d1 := getPooledDecompressor(codec)
+	if d1 == nil {
+		t.Fatal("getPooledDecompressor returned nil")
+	}
+	putPooledDecompressor(codec, d1)
+
+	d2 := getPooledDecompressor(codec)
+	if d2 != d1 {
+		t.Fatal("getPooledDecompressor did not return the instance just released")
+	}
+}
+
+// testPoolCompressLevel isolates this test's pool key from whatever level
+// other tests in this package might have already populated the shared
+// compressorPools map with.
+func testPoolCompressLevel() int { return -7 }
+
+type fakeCompressorFactory struct{}
+
+func (fakeCompressorFactory) NewCompressor(level int) zlibCompressor {
+	return &sysZLibCompressor{level: level}
+}
+func (fakeCompressorFactory) NewDecompressor() zlibDecompressor { return &sysZLibDecompressor{} }
+
+func TestRegisterCompressor(t *testing.T) {
+	defer delete(compressorFactories, "fake-test-codec")
+
+	RegisterCompressor("fake-test-codec", fakeCompressorFactory{})
+
+	if _, ok := compressorFactories["fake-test-codec"]; !ok {
+		t.Fatal("RegisterCompressor did not register the factory")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("RegisterCompressor did not panic on duplicate registration")
+		}
+	}()
+	RegisterCompressor("fake-test-codec", fakeCompressorFactory{})
+}
+
+func TestRegisterCompressorNilPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("RegisterCompressor did not panic on a nil factory")
+		}
+	}()
+	RegisterCompressor("fake-test-codec-nil", nil)
+}
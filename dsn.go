@@ -0,0 +1,265 @@
+// Copyright 2020 huiyi<yi.webmaster@hotmail.com>. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+// $Id: dsn.go
+
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidDSNNoSlash = errors.New("invalid DSN: missing the slash separating the database name")
+
+// Config is a configuration parsed from a DSN string.
+// If a new Config is created instead of being parsed from a DSN string,
+// the NewConfig function should be used, which sets default values.
+type Config struct {
+	User   string
+	Passwd string
+	Net    string
+	Addr   string
+	DBName string
+	Params map[string]string
+
+	Loc              *time.Location
+	MaxAllowedPacket int
+
+	Timeout      time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	AllowNativePasswords bool
+	CheckConnLiveness    bool
+
+	// CompressionAlgorithm selects the protocol_compression_algorithms value
+	// to negotiate with the server during the handshake, e.g. "zlib" or
+	// "zstd". Empty means zlib, the only algorithm every MySQL server that
+	// supports compression accepts.
+	CompressionAlgorithm string
+	// CompressLevel overrides the codec's default compression level. It is
+	// compressLevelUnset unless the DSN's compressLevel parameter set it.
+	CompressLevel int
+	// CompressMinSize overrides minCompressSize: packets smaller than this
+	// many bytes are sent uncompressed even on a compressed connection. 0
+	// means "use minCompressSize".
+	CompressMinSize int
+}
+
+// NewConfig creates a new Config and sets default values.
+func NewConfig() *Config {
+	return &Config{
+		MaxAllowedPacket:     maxPacketSize,
+		AllowNativePasswords: true,
+		CheckConnLiveness:    true,
+		CompressLevel:        compressLevelUnset,
+	}
+}
+
+// ParseDSN parses the DSN string to a Config.
+//
+//	[user[:password]@][net[(addr)]]/dbname[?param1=value1&paramN=valueN]
+func ParseDSN(dsn string) (cfg *Config, err error) {
+	cfg = NewConfig()
+
+	// Find the last '/' (since the password or the net addr might contain a '/')
+	foundSlash := false
+	for i := len(dsn) - 1; i >= 0; i-- {
+		if dsn[i] == '/' {
+			foundSlash = true
+			var j, k int
+
+			// left part is empty if i <= 0
+			if i > 0 {
+				// [username[:password]@][protocol[(address)]]
+				for j = i; j >= 0; j-- {
+					if dsn[j] == '@' {
+						// username[:password]
+						// Find the last ':' in dsn[:j]
+						for k = j - 1; k >= 0; k-- {
+							if dsn[k] == ':' {
+								cfg.Passwd = dsn[k+1 : j]
+								break
+							}
+						}
+						if k < 0 {
+							k = j
+						}
+						cfg.User = dsn[:k]
+						break
+					}
+				}
+
+				// [protocol[(address)]]
+				// Find the next '(' in dsn[j+1:i]
+				for k = j + 1; k < i; k++ {
+					if dsn[k] == '(' {
+						// dsn[i-1] must be == ')' if an address is specified
+						if dsn[i-1] != ')' {
+							if strings.ContainsRune(dsn[k+1:i], ')') {
+								return nil, errors.New("invalid DSN: did you forget to escape a param value?")
+							}
+							return nil, errors.New("invalid DSN: network address not terminated (missing closing brace)")
+						}
+						cfg.Addr = dsn[k+1 : i-1]
+						break
+					}
+				}
+				cfg.Net = dsn[j+1 : k]
+			}
+
+			// dbname[?param1=value1&...&paramN=valueN]
+			// Find the first '?' in dsn[i+1:]
+			for j = i + 1; j < len(dsn); j++ {
+				if dsn[j] == '?' {
+					if err = parseDSNParams(cfg, dsn[j+1:]); err != nil {
+						return nil, err
+					}
+					break
+				}
+			}
+			cfg.DBName = dsn[i+1 : j]
+
+			break
+		}
+	}
+
+	if !foundSlash && len(dsn) > 0 {
+		return nil, errInvalidDSNNoSlash
+	}
+
+	if cfg.Loc == nil {
+		cfg.Loc = time.UTC
+	}
+	if cfg.Net == "" {
+		cfg.Net = "tcp"
+	}
+
+	return
+}
+
+// parseDSNParams parses the DSN "query string"
+// Values must be url.QueryEscape'ed
+func parseDSNParams(cfg *Config, params string) (err error) {
+	for _, v := range strings.Split(params, "&") {
+		param := strings.SplitN(v, "=", 2)
+		if len(param) != 2 {
+			continue
+		}
+
+		// cfg params
+		switch value := param[1]; param[0] {
+
+		// Compression algorithm negotiated during the handshake:
+		// "zlib" or "zstd". Empty (the default) means zlib.
+		case "compress", "compression":
+			if lookupPacketCodec(value) == nil {
+				return fmt.Errorf("invalid DSN: unsupported compression algorithm %q", value)
+			}
+			if cfg.CompressLevel != compressLevelUnset {
+				if err := validateCompressLevel(value, cfg.CompressLevel); err != nil {
+					return err
+				}
+			}
+			cfg.CompressionAlgorithm = value
+
+		// Compression level, decoupled from "compress" so it can be tuned
+		// without switching algorithm, e.g. compress=zlib&compressLevel=9.
+		// Validated against the algorithm selected so far (zlib if none was
+		// given), and again by a later compress parameter, so a bad level is
+		// rejected here rather than on the first write.
+		case "compressLevel":
+			level, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid DSN compressLevel value: %q", value)
+			}
+			var algorithm = cfg.CompressionAlgorithm
+			if algorithm == "" {
+				algorithm = "zlib"
+			}
+			if err := validateCompressLevel(algorithm, level); err != nil {
+				return err
+			}
+			cfg.CompressLevel = level
+
+		// Minimum packet size worth compressing, honored by
+		// packetCompressor.writePacket instead of the minCompressSize
+		// constant.
+		case "compressMinSize":
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 0 {
+				return fmt.Errorf("invalid DSN compressMinSize value: %q", value)
+			}
+			cfg.CompressMinSize = size
+
+		case "allowNativePasswords":
+			var isBool bool
+			cfg.AllowNativePasswords, isBool = readBool(value)
+			if !isBool {
+				return errors.New("invalid bool value: " + value)
+			}
+
+		case "checkConnLiveness":
+			var isBool bool
+			cfg.CheckConnLiveness, isBool = readBool(value)
+			if !isBool {
+				return errors.New("invalid bool value: " + value)
+			}
+
+		case "maxAllowedPacket":
+			cfg.MaxAllowedPacket, err = strconv.Atoi(value)
+			if err != nil {
+				return
+			}
+
+		case "timeout":
+			cfg.Timeout, err = time.ParseDuration(value)
+			if err != nil {
+				return
+			}
+
+		case "readTimeout":
+			cfg.ReadTimeout, err = time.ParseDuration(value)
+			if err != nil {
+				return
+			}
+
+		case "writeTimeout":
+			cfg.WriteTimeout, err = time.ParseDuration(value)
+			if err != nil {
+				return
+			}
+
+		default:
+			// lazy init
+			if cfg.Params == nil {
+				cfg.Params = make(map[string]string)
+			}
+
+			if cfg.Params[param[0]], err = url.QueryUnescape(value); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// readBool reads a boolean value from a DSN param, accepting the same
+// spellings as the rest of this driver's boolean params.
+func readBool(input string) (value bool, valid bool) {
+	switch input {
+	case "1", "true", "TRUE", "True":
+		return true, true
+	case "0", "false", "FALSE", "False":
+		return false, true
+	}
+	return
+}
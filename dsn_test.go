@@ -0,0 +1,97 @@
+// Copyright 2020 huiyi<yi.webmaster@hotmail.com>. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+// $Id: dsn_test.go
+
+package mysql
+
+import "testing"
+
+func TestParseDSNCompressionAlgorithm(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		want    string
+		wantErr bool
+	}{
+		{dsn: "user:pass@tcp(localhost:3306)/db", want: ""},
+		{dsn: "user:pass@tcp(localhost:3306)/db?compress=zlib", want: "zlib"},
+		{dsn: "user:pass@tcp(localhost:3306)/db?compression=zstd", want: "zstd"},
+		{dsn: "user:pass@tcp(localhost:3306)/db?compress=bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		cfg, err := ParseDSN(tt.dsn)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDSN(%q): want error, got nil", tt.dsn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDSN(%q): unexpected error: %v", tt.dsn, err)
+			continue
+		}
+		if cfg.CompressionAlgorithm != tt.want {
+			t.Errorf("ParseDSN(%q): CompressionAlgorithm = %q, want %q", tt.dsn, cfg.CompressionAlgorithm, tt.want)
+		}
+	}
+}
+
+// TestParseDSNCompressLevelUnset is the chunk0-2 regression: compressLevel=-2
+// (zlib.HuffmanOnly) must reach Config.CompressLevel as -2, distinguishable
+// from "the DSN didn't set a level" (compressLevelUnset). Before
+// compressLevelUnset was changed from -2 to math.MinInt32, an explicit
+// request for Huffman-only compression was indistinguishable from "unset"
+// and silently fell back to the codec's default level instead.
+func TestParseDSNCompressLevelUnset(t *testing.T) {
+	cfg, err := ParseDSN("user:pass@tcp(localhost:3306)/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CompressLevel != compressLevelUnset {
+		t.Fatalf("default CompressLevel = %d, want compressLevelUnset (%d)", cfg.CompressLevel, compressLevelUnset)
+	}
+
+	cfg, err = ParseDSN("user:pass@tcp(localhost:3306)/db?compress=zlib&compressLevel=-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CompressLevel != -2 {
+		t.Fatalf("CompressLevel = %d, want -2 (zlib.HuffmanOnly)", cfg.CompressLevel)
+	}
+	if cfg.CompressLevel == compressLevelUnset {
+		t.Fatalf("CompressLevel=-2 (HuffmanOnly) must not alias compressLevelUnset (%d)", compressLevelUnset)
+	}
+}
+
+func TestParseDSNCompressLevelAndMinSize(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		wantErr bool
+	}{
+		{dsn: "user:pass@tcp(localhost:3306)/db?compress=zlib&compressLevel=6"},
+		{dsn: "user:pass@tcp(localhost:3306)/db?compress=zlib&compressLevel=11", wantErr: true},
+		{dsn: "user:pass@tcp(localhost:3306)/db?compressMinSize=1024"},
+		{dsn: "user:pass@tcp(localhost:3306)/db?compressMinSize=-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseDSN(tt.dsn)
+		if tt.wantErr && err == nil {
+			t.Errorf("ParseDSN(%q): want error, got nil", tt.dsn)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("ParseDSN(%q): unexpected error: %v", tt.dsn, err)
+		}
+	}
+
+	cfg, err := ParseDSN("user:pass@tcp(localhost:3306)/db?compressMinSize=1024")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CompressMinSize != 1024 {
+		t.Fatalf("CompressMinSize = %d, want 1024", cfg.CompressMinSize)
+	}
+}
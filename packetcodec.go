@@ -0,0 +1,114 @@
+// Copyright 2020 huiyi<yi.webmaster@hotmail.com>. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+// $Id: packetcodec.go
+
+package mysql
+
+import (
+	"compress/zlib"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// packetCodec identifies one of the algorithms listed in MySQL 8.0's
+// protocol_compression_algorithms ("zlib" or "zstd"), negotiated during the
+// handshake via the client capability flags or requested explicitly through
+// the DSN's compress/compression option. The compressed packet header
+// format is identical for every algorithm, so a codec only needs to build
+// the compressor/decompressor pair and supply the level to use when the
+// caller doesn't ask for a specific one.
+type packetCodec interface {
+	// name is the value used in protocol_compression_algorithms and the DSN.
+	name() string
+	newCompressor(level int) zlibCompressor
+	newDecompressor() zlibDecompressor
+	defaultLevel() int
+	// validateLevel reports whether level is one this codec accepts, so the
+	// DSN's compressLevel parameter can be rejected at parse time instead
+	// of failing on the first write.
+	validateLevel(level int) error
+}
+
+// zlibCodec is the original, always-available compression algorithm.
+type zlibCodec struct{}
+
+func (zlibCodec) name() string { return "zlib" }
+
+func (zlibCodec) newCompressor(level int) zlibCompressor {
+	if f, ok := compressorFactories["zlib"]; ok {
+		return f.NewCompressor(level)
+	}
+	return &sysZLibCompressor{level: level}
+}
+
+func (zlibCodec) newDecompressor() zlibDecompressor {
+	if f, ok := compressorFactories["zlib"]; ok {
+		return f.NewDecompressor()
+	}
+	return &sysZLibDecompressor{}
+}
+
+func (zlibCodec) defaultLevel() int { return zlib.DefaultCompression }
+
+func (zlibCodec) validateLevel(level int) error {
+	if level == zlib.DefaultCompression || level == zlib.HuffmanOnly ||
+		(level >= zlib.NoCompression && level <= zlib.BestCompression) {
+		return nil
+	}
+	return fmt.Errorf("mysql: invalid compressLevel %d for zlib, want %d..%d, %d or %d", level, zlib.NoCompression, zlib.BestCompression, zlib.DefaultCompression, zlib.HuffmanOnly)
+}
+
+// zstdCodec is MySQL 8.0's second supported algorithm.
+type zstdCodec struct{}
+
+func (zstdCodec) name() string { return "zstd" }
+
+func (zstdCodec) newCompressor(level int) zlibCompressor {
+	if f, ok := compressorFactories["zstd"]; ok {
+		return f.NewCompressor(level)
+	}
+	return &zstdCompressor{level: level}
+}
+
+func (zstdCodec) newDecompressor() zlibDecompressor {
+	if f, ok := compressorFactories["zstd"]; ok {
+		return f.NewDecompressor()
+	}
+	return &zstdDecompressor{}
+}
+
+func (zstdCodec) defaultLevel() int { return int(zstdDefaultLevel) }
+
+func (zstdCodec) validateLevel(level int) error {
+	if level >= int(zstd.SpeedFastest) && level <= int(zstd.SpeedBestCompression) {
+		return nil
+	}
+	return fmt.Errorf("mysql: invalid compressLevel %d for zstd, want %d..%d", level, zstd.SpeedFastest, zstd.SpeedBestCompression)
+}
+
+// packetCodecs maps a protocol_compression_algorithms name to its codec.
+var packetCodecs = map[string]packetCodec{
+	zlibCodec{}.name(): zlibCodec{},
+	zstdCodec{}.name(): zstdCodec{},
+}
+
+// lookupPacketCodec returns the packetCodec registered for name, or nil if
+// name is empty or not a supported compression algorithm.
+func lookupPacketCodec(name string) packetCodec {
+	return packetCodecs[name]
+}
+
+// validateCompressLevel checks that level is acceptable for algorithm. It is
+// called while parsing the DSN's compressLevel parameter so a bad value is
+// rejected at sql.Open time rather than on the connection's first write.
+func validateCompressLevel(algorithm string, level int) error {
+	var codec = lookupPacketCodec(algorithm)
+	if codec == nil {
+		codec = zlibCodec{}
+	}
+	return codec.validateLevel(level)
+}
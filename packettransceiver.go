@@ -9,8 +9,9 @@ package mysql
 
 import (
 	"bytes"
-	"compress/zlib"
 	"database/sql/driver"
+	"io"
+	"math"
 	"net"
 	"time"
 )
@@ -31,16 +32,22 @@ import (
 const (
 	// Initialize buffer size for compression
 	defaultCompressBufferSize = 1024 * 16
-	// Initialize buffer size for decompression
-	defaultDecompressBufferSize = 1024 * 16
-	// Only affects send data
-	defaultPacketCompressLevel = zlib.DefaultCompression
-	// if packet size < minCompressSize, do not compress
+	// Ring buffer size used to stream decompressed bytes out of a
+	// compressed frame instead of inflating it into one big allocation
+	defaultDecompressBufferSize = 1024 * 64
+	// if packet size < minCompressSize, do not compress; used unless the
+	// DSN's compressMinSize overrides it
 	minCompressSize = 100
 	// [0..2] length [3]byte; [3] sequence
 	packetHeaderSize = 4
 	// [0..2] payload [3]byte, [3] sync compression sequence; [4..6] uncompressed payload
 	compressedHeaderSize = 7
+	// compressLevelUnset marks Config.CompressLevel as "not set by the
+	// DSN", so the connection falls back to the codec's own default level.
+	// math.MinInt32 rather than e.g. -2 because -2 is itself a valid level
+	// (zlib.HuffmanOnly, see compress/flate.HuffmanOnly) that a DSN's
+	// compressLevel parameter can legitimately request.
+	compressLevelUnset = math.MinInt32
 )
 
 /*
@@ -61,18 +68,24 @@ type zlibCompressor interface {
 }
 
 type zlibDecompressor interface {
-	// In mysql compress protocol, we always know the decompressed length for input
-	decompress(input []byte, output []byte) error
+	io.Reader
+	// reset prepares the decompressor to inflate a new compressed frame's bytes
+	reset(input []byte) error
 }
 
 type packetDecompressor struct {
-	buffer       []byte // store decompressed bytes
-	index        int
-	decompressor zlibDecompressor
+	ring         []byte    // small ring buffer of already-inflated bytes, allocated lazily on first use
+	start, end   int       // ring[start:end] holds buffered, not yet consumed bytes
+	reader       io.Reader // source for the frame currently being streamed out
+	remaining    int       // bytes left to pull from reader for the in-progress frame
+	codec        packetCodec
+	decompressor zlibDecompressor // reused inflate stream across frames
 }
 
 type packetCompressor struct {
 	buffer     bytes.Buffer // store compressed bytes
+	codec      packetCodec
+	level      int // level the pooled compressor was built with, for release
 	compressor zlibCompressor
 }
 
@@ -81,6 +94,41 @@ type packetTransceiver struct {
 	decompressor packetDecompressor
 }
 
+// release returns any compressor/decompressor obtained from the shared pool
+// back to it. readPacket calls this before giving up on a connection after a
+// fatal read error or a malformed packet, and writePacket after a failed
+// stale idle connection check, so the pooled instances they were using
+// become available to a future connection instead of being discarded along
+// with mc. writePacket's send error paths close the connection without
+// calling it.
+func (pb *packetTransceiver) release() {
+	pb.compressor.release()
+	pb.decompressor.release()
+}
+
+func (pc *packetCompressor) release() {
+	if pc.compressor != nil && pc.codec != nil {
+		putPooledCompressor(pc.codec, pc.level, pc.compressor)
+		pc.compressor = nil
+	}
+}
+
+func (pd *packetDecompressor) release() {
+	if pd.decompressor != nil && pd.codec != nil {
+		putPooledDecompressor(pd.codec, pd.decompressor)
+		pd.decompressor = nil
+	}
+}
+
+// minCompressSizeFor returns the minCompressSize threshold to use for mc,
+// honoring the DSN's compressMinSize when the user set one.
+func minCompressSizeFor(mc *mysqlConn) int {
+	if mc.cfg.CompressMinSize > 0 {
+		return mc.cfg.CompressMinSize
+	}
+	return minCompressSize
+}
+
 func sendToNetwork(conn net.Conn, data []byte) (int, error) {
 	var err error
 	var index int = 0
@@ -129,44 +177,63 @@ func (h *compressedPacketHeader) reset(header []byte) {
 	copy(h[:], header)
 }
 
+// readNext returns the next need bytes of decompressed packet data. It pulls
+// additional compressed frames through the inflate stream only as far as
+// necessary, rather than buffering a whole frame upfront, so a single large
+// compressed frame no longer forces one big contiguous allocation.
 func (pd *packetDecompressor) readNext(mc *mysqlConn, need int) ([]byte, error) {
-	if len(pd.buffer)-pd.index < need {
-		for {
-			var err = pd.decompressPacket(mc)
-			if err != nil {
+	var result = make([]byte, need)
+	var filled int
+
+	for filled < need {
+		if pd.start == pd.end {
+			if err := pd.fill(mc); err != nil {
 				return nil, err
 			}
-			if len(pd.buffer)-pd.index >= need {
-				break
-			}
 		}
+		var n = copy(result[filled:], pd.ring[pd.start:pd.end])
+		filled += n
+		pd.start += n
 	}
-	var result []byte = pd.buffer[pd.index : pd.index+need]
-	pd.index += need
 	return result, nil
 }
 
-// Decompress one packet, and append the decompressed data to buffer
-func (pd *packetDecompressor) decompressPacket(mc *mysqlConn) error {
-	var err error
-	var headerBuf []byte
-	var header compressedPacketHeader
-	var payload int
-	var length int  // uncompressed length
-	var data []byte // packet body
-	// var decompressedData []byte
-
-	// Initialize buffer
-	if pd.buffer == nil {
-		pd.buffer = make([]byte, 0, defaultDecompressBufferSize)
+// fill pulls the next slice of inflated bytes into the ring buffer,
+// advancing to the next compressed frame once the current one is exhausted.
+func (pd *packetDecompressor) fill(mc *mysqlConn) error {
+	for pd.remaining == 0 {
+		if err := pd.nextFrame(mc); err != nil {
+			return err
+		}
+	}
+
+	if pd.ring == nil {
+		pd.ring = make([]byte, defaultDecompressBufferSize)
+	}
+
+	var want = len(pd.ring)
+	if want > pd.remaining {
+		want = pd.remaining
 	}
 
-	// If no data in the buffer, shrink it
-	if pd.index > 0 && pd.index == len(pd.buffer) {
-		pd.index = 0
-		pd.buffer = pd.buffer[:0]
+	var n, err = io.ReadFull(pd.reader, pd.ring[:want])
+	if err != nil {
+		return err
 	}
 
+	pd.start, pd.end = 0, n
+	pd.remaining -= n
+	return nil
+}
+
+// nextFrame reads the next compressed frame's header and body, checking the
+// sequence/boundary as before, and arranges for pd.reader to stream its
+// uncompressed bytes.
+func (pd *packetDecompressor) nextFrame(mc *mysqlConn) error {
+	var err error
+	var headerBuf []byte
+	var header compressedPacketHeader
+
 	// Parse Header
 	headerBuf, err = mc.buf.readNext(7)
 	if err != nil {
@@ -184,63 +251,50 @@ func (pd *packetDecompressor) decompressPacket(mc *mysqlConn) error {
 	mc.compressionSequence++
 
 	// Read compressed packet data
-	payload, length = header.payload(), header.uncompressedLength()
+	var payload, length = header.payload(), header.uncompressedLength()
+	var data []byte
 	data, err = mc.buf.readNext(payload)
 	if err != nil {
 		return err
 	}
 
-	// If payload is not compressed
+	// If payload is not compressed, stream it back out as-is
 	if length == 0 {
-		pd.grow(payload)
-		pd.buffer = append(pd.buffer, data...)
+		pd.reader = bytes.NewReader(data)
+		pd.remaining = payload
 		return nil
 	}
 
-	// Ensure enough buffer for decompression
-	pd.grow(length)
-
-	// Initialize decompressor
+	// Initialize decompressor, picking the algorithm negotiated for this
+	// connection (zlib by default, or zstd when the handshake / DSN asked
+	// for it)
 	if pd.decompressor == nil {
-		pd.decompressor = &sysZLibDecompressor{}
-		/*
-		if UseZLibCgo {
-			pd.decompressor = &cgoZLibDecompressor{}
-		} else {
-			pd.decompressor = &sysZLibDecompressor{}
+		if pd.codec == nil {
+			pd.codec = lookupPacketCodec(mc.cfg.CompressionAlgorithm)
 		}
-		 */
+		if pd.codec == nil {
+			pd.codec = zlibCodec{}
+		}
+		pd.decompressor = getPooledDecompressor(pd.codec)
 	}
 
-	// Decompress data
-	err = pd.decompressor.decompress(data, pd.buffer[len(pd.buffer):len(pd.buffer)+length])
-	if err != nil {
+	if err = pd.decompressor.reset(data); err != nil {
 		return err
 	}
 
-	// Reset buffer length
-	pd.buffer = pd.buffer[:len(pd.buffer)+length]
+	pd.reader = pd.decompressor
+	pd.remaining = length
 	return nil
 }
 
-// Ensures enough space for append packet data to buffer
-func (pd *packetDecompressor) grow(dataLength int) {
-	// If there's no room for packet data
-	if len(pd.buffer)+dataLength > cap(pd.buffer) {
-		// Buffer capacity is enough, shrink it
-		if len(pd.buffer)-pd.index+dataLength <= cap(pd.buffer) {
-			var newLength int = len(pd.buffer) - pd.index
-			copy(pd.buffer[:newLength], pd.buffer[pd.index:])
-			pd.buffer = pd.buffer[:newLength]
-			pd.index = 0
-		} else {
-			// Allocate new buffer
-			var newBuffer = make([]byte, 0, len(pd.buffer)-pd.index+dataLength)
-			pd.buffer = append(newBuffer, pd.buffer[pd.index:len(pd.buffer)]...)
-			pd.index = 0
-		}
-	}
-}
+// maxCompressedChunkSize is the most packet bytes that may be handed to
+// writeToBuffer in one call. packet here is a full mysql packet including
+// its 4-byte header (up to packetHeaderSize+maxPacketSize bytes), but a
+// compressed frame's "uncompressed payload length" field is only 3 bytes
+// wide, the same as the mysql packet length field. Capping the chunk at
+// maxPacketSize-packetHeaderSize guarantees it always fits that field,
+// whether or not the chunk ends up compressed.
+const maxCompressedChunkSize = maxPacketSize - packetHeaderSize
 
 // The very very rarely case is len(packet) = len(packet header) + len(packet data) > 0xFFFFFF, but can not be
 // compressed. In this case, the origin packet will be splited into 2 compressed packets:
@@ -259,10 +313,13 @@ func (pc *packetCompressor) writePacket(mc *mysqlConn, packet []byte) (int, erro
 
 	for index < len(packet) {
 		var err error
-		var remain = len(packet) - index
+		var chunk = packet[index:]
+		if len(chunk) > maxCompressedChunkSize {
+			chunk = chunk[:maxCompressedChunkSize]
+		}
 		var bytesWritten int
 
-		bytesWritten, err = pc.writeToBuffer(packet[index:], mc.compressionSequence, remain < minCompressSize)
+		bytesWritten, err = pc.writeToBuffer(mc, chunk, mc.compressionSequence, len(chunk) < minCompressSizeFor(mc))
 		if err != nil {
 			return index, err
 		}
@@ -288,7 +345,7 @@ func (pc *packetCompressor) writePacket(mc *mysqlConn, packet []byte) (int, erro
 	return len(packet), nil
 }
 
-func (pc *packetCompressor) writeToBuffer(data []byte, sequence uint8, compress bool) (int, error) {
+func (pc *packetCompressor) writeToBuffer(mc *mysqlConn, data []byte, sequence uint8, compress bool) (int, error) {
 	var err error
 	var header compressedPacketHeader
 	var compressedLength int
@@ -322,16 +379,21 @@ func (pc *packetCompressor) writeToBuffer(data []byte, sequence uint8, compress
 		return size, nil
 	}
 
-	// Initialize compressor
+	// Initialize compressor, picking the algorithm negotiated for this
+	// connection (zlib by default, or zstd when the handshake / DSN asked
+	// for it)
 	if pc.compressor == nil {
-		pc.compressor = &sysZLibCompressor{}
-		/*
-		if UseZLibCgo {
-			pc.compressor = &cgoZLibCompressor{}
-		} else {
-			pc.compressor = &sysZLibCompressor{}
+		if pc.codec == nil {
+			pc.codec = lookupPacketCodec(mc.cfg.CompressionAlgorithm)
+		}
+		if pc.codec == nil {
+			pc.codec = zlibCodec{}
+		}
+		pc.level = pc.codec.defaultLevel()
+		if mc.cfg.CompressLevel != compressLevelUnset {
+			pc.level = mc.cfg.CompressLevel
 		}
-		 */
+		pc.compressor = getPooledCompressor(pc.codec, pc.level)
 	}
 
 	// Keep memory for compressed header
@@ -340,9 +402,11 @@ func (pc *packetCompressor) writeToBuffer(data []byte, sequence uint8, compress
 	// Compress data
 	compressedLength, err = pc.compressor.compress(data, &pc.buffer)
 
-	// If compression is not helpful, do not compress
+	// If compression is not helpful, do not compress. This length check is
+	// algorithm-agnostic, so it fallbacks correctly for zstd's typically
+	// larger worst-case output just as it does for zlib.
 	if compressedLength > maxPacketSize || compressedLength > len(data) {
-		return pc.writeToBuffer(data, sequence, false)
+		return pc.writeToBuffer(mc, data, sequence, false)
 	}
 
 	// Write header
@@ -372,6 +436,7 @@ func (pb *packetTransceiver) readPacket(mc *mysqlConn, compress bool) ([]byte, e
 				return nil, cerr
 			}
 			errLog.Print(err)
+			pb.release()
 			mc.Close()
 			return nil, ErrInvalidConn
 		}
@@ -394,6 +459,7 @@ func (pb *packetTransceiver) readPacket(mc *mysqlConn, compress bool) ([]byte, e
 			// there was no previous packet
 			if prevData == nil {
 				errLog.Print(ErrMalformPkt)
+				pb.release()
 				mc.Close()
 				return nil, ErrInvalidConn
 			}
@@ -413,6 +479,7 @@ func (pb *packetTransceiver) readPacket(mc *mysqlConn, compress bool) ([]byte, e
 				return nil, cerr
 			}
 			errLog.Print(err)
+			pb.release()
 			mc.Close()
 			return nil, ErrInvalidConn
 		}
@@ -463,6 +530,7 @@ func (pb *packetTransceiver) writePacket(mc *mysqlConn, data []byte, compress bo
 		}
 		if err != nil {
 			errLog.Print("closing bad idle connection: ", err)
+			pb.release()
 			mc.Close()
 			return driver.ErrBadConn
 		}
@@ -0,0 +1,71 @@
+// Copyright 2020 huiyi<yi.webmaster@hotmail.com>. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+// $Id: packettransceiver_test.go
+
+package mysql
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"os"
+	"testing"
+)
+
+// compressTestDSN returns the DSN used by the compressed-packet round-trip
+// test, or "" if it isn't configured. The server it points at must have
+// max_allowed_packet raised to at least 20MB, since this test pushes a
+// packet just over the 0xFFFFFF single-compressed-frame boundary.
+func compressTestDSN() string {
+	return os.Getenv("MYSQL_TEST_COMPRESS_DSN")
+}
+
+// TestCompressLargeIncompressiblePacket reproduces the chunk0-3 regression:
+// a packet whose total length (header + data) exceeds 0xFFFFFF bytes but
+// doesn't compress down below that boundary must be split into two
+// compressed frames by packetCompressor.writePacket rather than having its
+// 3-byte uncompressed-length field silently truncated. Random bytes are
+// used as the payload because they're incompressible, forcing that split.
+func TestCompressLargeIncompressiblePacket(t *testing.T) {
+	dsn := compressTestDSN()
+	if dsn == "" {
+		t.Skip("MYSQL_TEST_COMPRESS_DSN not set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("DROP TABLE IF EXISTS test_compress_large"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE test_compress_large (data LONGBLOB)"); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Exec("DROP TABLE test_compress_large")
+
+	// One byte over maxPacketSize so a single compressed packet must split
+	// into two compressed frames even though it never compresses smaller.
+	want := make([]byte, maxPacketSize+1)
+	if _, err := rand.Read(want); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec("INSERT INTO test_compress_large VALUES (?)", want); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []byte
+	if err := db.QueryRow("SELECT data FROM test_compress_large").Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Fatalf("round-tripped blob corrupted: got %d bytes, want %d bytes", len(got), len(want))
+	}
+}
@@ -8,7 +8,6 @@
 package mysql
 
 import (
-	"bufio"
 	"bytes"
 	"compress/zlib"
 	"io"
@@ -17,13 +16,13 @@ import (
 // Compress & decompress mysql packet using golang zlib
 
 type sysZLibCompressor struct {
+	level      int
 	zlibWriter *zlib.Writer
 }
 
 type sysZLibDecompressor struct {
-	bytesReader    bytes.Reader
-	bufferedReader bufio.Reader // Make zlib.flate.makeReader happy, but 1 more time memory copy!!
-	zlibReader     io.ReadCloser
+	bytesReader bytes.Reader
+	zlibReader  io.ReadCloser
 }
 
 func (zc *sysZLibCompressor) compress(input []byte, output *bytes.Buffer) (int, error) {
@@ -32,7 +31,7 @@ func (zc *sysZLibCompressor) compress(input []byte, output *bytes.Buffer) (int,
 
 	// Initialize zlib writer
 	if zc.zlibWriter == nil {
-		zc.zlibWriter, err = zlib.NewWriterLevel(output, defaultPacketCompressLevel)
+		zc.zlibWriter, err = zlib.NewWriterLevel(output, zc.level)
 	} else {
 		// Reuse zlib writer
 		zc.zlibWriter.Reset(output)
@@ -62,31 +61,26 @@ func (zc *sysZLibCompressor) compress(input []byte, output *bytes.Buffer) (int,
 	return output.Len() - lenSave, nil
 }
 
-// In mysql compress protocol, we always know the decompressed length for input
-func (zd *sysZLibDecompressor) decompress(input []byte, output []byte) error {
+// reset prepares zd to inflate a new compressed frame's bytes. bytes.Reader
+// already implements io.ByteReader, so unlike the old one-shot decompress,
+// this no longer needs a bufio.Reader wrapper just to keep zlib happy.
+func (zd *sysZLibDecompressor) reset(input []byte) error {
 	var err error
 
-	// Reuse bytes.Buffer
+	// Reuse bytes.Reader
 	zd.bytesReader.Reset(input)
 
 	// Initialize zlib reader
 	if zd.zlibReader == nil {
-		zd.bufferedReader = *bufio.NewReaderSize(&zd.bytesReader, 512)
-		zd.zlibReader, err = zlib.NewReader(&zd.bufferedReader)
+		zd.zlibReader, err = zlib.NewReader(&zd.bytesReader)
 	} else {
 		// Reuse zlib reader
-		zd.bufferedReader.Reset(&zd.bytesReader)
-		err = zd.zlibReader.(zlib.Resetter).Reset(&zd.bufferedReader, nil)
-	}
-	if  err != nil {
-		return err
-	}
-
-	// n of io.ReadFull = len(output) when err = null, so the return value can be ignored
-	_, err = io.ReadFull(zd.zlibReader, output)
-	if err != nil {
-		return err
+		err = zd.zlibReader.(zlib.Resetter).Reset(&zd.bytesReader, nil)
 	}
+	return err
+}
 
-	return nil
+// Read streams the inflated bytes of the frame passed to the last reset.
+func (zd *sysZLibDecompressor) Read(p []byte) (int, error) {
+	return zd.zlibReader.Read(p)
 }
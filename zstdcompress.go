@@ -0,0 +1,87 @@
+// Copyright 2020 huiyi<yi.webmaster@hotmail.com>. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+// $Id: zstdcompress.go
+
+package mysql
+
+import (
+	"bytes"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compress & decompress mysql packets using zstd, MySQL 8.0's second
+// protocol_compression_algorithms value. klauspost/compress is a pure Go
+// implementation, so picking zstd adds no cgo dependency.
+
+// zstdDefaultLevel plays the part zlib.DefaultCompression plays for zlib: the
+// level used when the DSN sets none. It is zstd.SpeedDefault, the library's
+// own default, which is level 2 of the 1..4 range zstdCodec.validateLevel
+// accepts.
+const zstdDefaultLevel = int(zstd.SpeedDefault)
+
+type zstdCompressor struct {
+	level   int
+	encoder *zstd.Encoder
+}
+
+type zstdDecompressor struct {
+	decoder *zstd.Decoder
+}
+
+func (zc *zstdCompressor) compress(input []byte, output *bytes.Buffer) (int, error) {
+	var err error
+	var lenSave = output.Len()
+
+	// Initialize zstd encoder. WithEncoderConcurrency(1) keeps this to the
+	// single background goroutine zstd.NewWriter always starts regardless
+	// of concurrency, instead of the extra worker goroutines the default
+	// (GOMAXPROCS) would add per pooled encoder.
+	if zc.encoder == nil {
+		zc.encoder, err = zstd.NewWriter(output,
+			zstd.WithEncoderLevel(zstd.EncoderLevel(zc.level)),
+			zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		// Reuse zstd encoder
+		zc.encoder.Reset(output)
+	}
+
+	// Compress data
+	for len(input) > 0 {
+		var n int
+		n, err = zc.encoder.Write(input)
+		if err != nil {
+			return 0, err
+		}
+		input = input[n:]
+	}
+	err = zc.encoder.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	// Returns compressed length
+	return output.Len() - lenSave, nil
+}
+
+// reset prepares zd to inflate a new compressed frame's bytes.
+func (zd *zstdDecompressor) reset(input []byte) error {
+	var err error
+
+	if zd.decoder == nil {
+		zd.decoder, err = zstd.NewReader(bytes.NewReader(input), zstd.WithDecoderConcurrency(1))
+		return err
+	}
+	return zd.decoder.Reset(bytes.NewReader(input))
+}
+
+// Read streams the inflated bytes of the frame passed to the last reset.
+func (zd *zstdDecompressor) Read(p []byte) (int, error) {
+	return zd.decoder.Read(p)
+}
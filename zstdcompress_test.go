@@ -0,0 +1,56 @@
+// Copyright 2020 huiyi<yi.webmaster@hotmail.com>. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+// $Id: zstdcompress_test.go
+
+package mysql
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestZstdCompressorRoundTrip(t *testing.T) {
+	want := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 1000)
+
+	zc := &zstdCompressor{level: zstdDefaultLevel}
+	var buf bytes.Buffer
+	if _, err := zc.compress(want, &buf); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+
+	zd := &zstdDecompressor{}
+	if err := zd.reset(buf.Bytes()); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	got, err := io.ReadAll(zd)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Fatalf("round trip mismatch: got %d bytes, want %d bytes", len(got), len(want))
+	}
+
+	// The compressor and decompressor must both be reusable across frames,
+	// since packetCompressor/packetDecompressor keep one of each around
+	// for the lifetime of a connection.
+	buf.Reset()
+	want2 := bytes.Repeat([]byte("second frame "), 500)
+	if _, err := zc.compress(want2, &buf); err != nil {
+		t.Fatalf("compress (2nd frame): %v", err)
+	}
+	if err := zd.reset(buf.Bytes()); err != nil {
+		t.Fatalf("reset (2nd frame): %v", err)
+	}
+	got2, err := io.ReadAll(zd)
+	if err != nil {
+		t.Fatalf("read (2nd frame): %v", err)
+	}
+	if !bytes.Equal(got2, want2) {
+		t.Fatalf("round trip mismatch (2nd frame): got %d bytes, want %d bytes", len(got2), len(want2))
+	}
+}